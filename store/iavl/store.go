@@ -0,0 +1,160 @@
+package iavl
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tendermint/iavl"
+	abci "github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/cosmos/cosmos-sdk/store/proof"
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+const defaultIAVLCacheSize = 10000
+
+var (
+	_ types.KVStore       = (*Store)(nil)
+	_ types.CommitKVStore = (*Store)(nil)
+	_ types.Queryable     = (*Store)(nil)
+)
+
+// Store wraps an iavl.MutableTree and adapts it to the store/types
+// interfaces used by rootmulti.
+type Store struct {
+	tree *iavl.MutableTree
+}
+
+// NewKey builds the KVStoreKey used to mount an IAVL-backed store.
+func NewKey(name string) types.KVStoreKey {
+	return types.NewKVStoreKey(name)
+}
+
+// LoadStore loads (or creates, if version is 0) an IAVL store at the given
+// version from db.
+func LoadStore(db dbm.DB, id types.CommitID) (*Store, error) {
+	tree := iavl.NewMutableTree(db, defaultIAVLCacheSize)
+
+	_, err := tree.LoadVersion(id.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{tree: tree}, nil
+}
+
+func (st *Store) GetStoreType() types.StoreType {
+	return types.StoreTypeIAVL
+}
+
+// SetInitialVersion tells the tree's first Commit to land on version
+// instead of 1. It only has an effect before that first commit (an IAVL
+// tree only consults it while saving version 1), which is what lets
+// rootmulti's Restore seed a freshly loaded, empty store straight to a
+// snapshot's height without replaying every version up to it.
+func (st *Store) SetInitialVersion(version int64) {
+	st.tree.SetInitialVersion(uint64(version))
+}
+
+// Commit persists the current working tree as a new version.
+func (st *Store) Commit() types.CommitID {
+	hash, version, err := st.tree.SaveVersion()
+	if err != nil {
+		panic(err)
+	}
+
+	return types.CommitID{Version: version, Hash: hash}
+}
+
+func (st *Store) LastCommitID() types.CommitID {
+	return types.CommitID{
+		Version: st.tree.Version(),
+		Hash:    st.tree.Hash(),
+	}
+}
+
+// DeleteVersion removes a historical version from the underlying tree. It is
+// a no-op if the version was already pruned or never existed.
+func (st *Store) DeleteVersion(version int64) error {
+	if !st.tree.VersionExists(version) {
+		return nil
+	}
+	return st.tree.DeleteVersion(version)
+}
+
+func (st *Store) Get(key []byte) []byte {
+	_, value := st.tree.Get(key)
+	return value
+}
+
+func (st *Store) Has(key []byte) bool {
+	return st.tree.Has(key)
+}
+
+func (st *Store) Set(key, value []byte) {
+	st.tree.Set(key, value)
+}
+
+func (st *Store) Delete(key []byte) {
+	st.tree.Remove(key)
+}
+
+func (st *Store) Iterator(start, end []byte) types.Iterator {
+	return st.tree.Iterator(start, end, true)
+}
+
+func (st *Store) ReverseIterator(start, end []byte) types.Iterator {
+	return st.tree.Iterator(start, end, false)
+}
+
+// GetWithProof looks up key and also returns the IAVL range proof attesting
+// to its presence (or, if value is nil, its absence) in the tree at its
+// current version.
+func (st *Store) GetWithProof(key []byte) (value []byte, rangeProof *iavl.RangeProof, err error) {
+	return st.tree.GetWithProof(key)
+}
+
+// Query implements types.Queryable, answering /key queries against the
+// tree. When req.Prove is set, res.Proof carries a JSON-encoded list holding
+// a single proof.ProofOp attesting to the (non-)existence of the key within this
+// store; rootmulti.Query composes it with the cross-store proof that this
+// store's own root is included in the multistore root.
+func (st *Store) Query(req abci.RequestQuery) (res abci.ResponseQuery) {
+	if len(req.Data) == 0 {
+		res.Code = uint32(types.ToABCICode(types.CodeUnknownRequest))
+		res.Log = "query cannot be zero length"
+		return
+	}
+
+	switch req.Path {
+	case "/key":
+		key := req.Data
+
+		if !req.Prove {
+			res.Value = st.Get(key)
+			return
+		}
+
+		value, rangeProof, err := st.GetWithProof(key)
+		if err != nil {
+			res.Code = uint32(types.ToABCICode(types.CodeUnknownRequest))
+			res.Log = err.Error()
+			return
+		}
+
+		bz, err := json.Marshal([]proof.ProofOp{proof.NewCommitmentOp(key, value != nil, rangeProof).ProofOp()})
+		if err != nil {
+			panic(fmt.Sprintf("failed to marshal IAVL proof op: %v", err))
+		}
+
+		res.Value = value
+		res.Proof = bz
+		return
+
+	default:
+		res.Code = uint32(types.ToABCICode(types.CodeUnknownRequest))
+		res.Log = fmt.Sprintf("unexpected query path: %v", req.Path)
+		return
+	}
+}
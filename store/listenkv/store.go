@@ -0,0 +1,67 @@
+package listenkv
+
+import (
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+var _ types.CommitKVStore = (*Store)(nil)
+
+// Store is a CommitKVStore wrapper that fans every Set/Delete out to a set
+// of WriteListeners before returning, so external subsystems can observe
+// writes without the wrapped store knowing anything about them. All other
+// methods pass straight through to the parent.
+type Store struct {
+	parent    types.CommitKVStore
+	storeKey  types.StoreKey
+	listeners []types.WriteListener
+}
+
+// NewStore wraps parent so every Set/Delete on it is reported to listeners,
+// tagged with storeKey.
+func NewStore(parent types.CommitKVStore, storeKey types.StoreKey, listeners []types.WriteListener) *Store {
+	return &Store{parent: parent, storeKey: storeKey, listeners: listeners}
+}
+
+func (s *Store) GetStoreType() types.StoreType {
+	return s.parent.GetStoreType()
+}
+
+func (s *Store) Commit() types.CommitID {
+	return s.parent.Commit()
+}
+
+func (s *Store) LastCommitID() types.CommitID {
+	return s.parent.LastCommitID()
+}
+
+func (s *Store) Get(key []byte) []byte {
+	return s.parent.Get(key)
+}
+
+func (s *Store) Has(key []byte) bool {
+	return s.parent.Has(key)
+}
+
+func (s *Store) Iterator(start, end []byte) types.Iterator {
+	return s.parent.Iterator(start, end)
+}
+
+func (s *Store) ReverseIterator(start, end []byte) types.Iterator {
+	return s.parent.ReverseIterator(start, end)
+}
+
+func (s *Store) Set(key, value []byte) {
+	s.parent.Set(key, value)
+	s.emit(key, value, false)
+}
+
+func (s *Store) Delete(key []byte) {
+	s.parent.Delete(key)
+	s.emit(key, nil, true)
+}
+
+func (s *Store) emit(key, value []byte, delete bool) {
+	for _, l := range s.listeners {
+		l.OnWrite(s.storeKey, key, value, delete)
+	}
+}
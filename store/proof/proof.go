@@ -0,0 +1,197 @@
+// Package proof defines the composable proof-op chain returned by
+// rootmulti.Store.Query when a request asks to be proven: an IAVL
+// commitment proof that a key/value pair belongs to one substore, followed
+// by a Merkle proof that the substore's CommitID.Hash is itself included in
+// the multistore root. A light client verifies the whole chain end-to-end
+// with nothing but the query response and the block header's app hash.
+package proof
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tendermint/iavl"
+	"github.com/tendermint/tendermint/crypto/merkle"
+
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+// Op-type tags carried on the wire so a ProofOp can be decoded back into the
+// right concrete Op.
+const (
+	OpTypeIAVL        = "ics23:iavl"
+	OpTypeSimpleStore = "ics23:simple"
+)
+
+// Op is one link in a proof chain: given the already-verified output of the
+// op below it (or, for the first op, the raw value being proven), it
+// returns the root hash implied one level up.
+type Op interface {
+	Run(args [][]byte) ([][]byte, error)
+	ProofOp() ProofOp
+}
+
+// ProofOp is the wire form of an Op: a type tag plus whatever bytes that
+// type needs to reconstruct and verify itself, given only the key it's
+// proving something about.
+type ProofOp struct {
+	Type string
+	Key  []byte
+	Data []byte
+}
+
+// CommitmentOp proves that a key/value pair exists, or (when Exists is
+// false) that the key is absent, in a single IAVL substore. Exists is
+// decided by the store that produced Proof (from whether its lookup
+// actually found the key), not inferred from the value being verified:
+// a key legitimately holding an empty value is still present.
+type CommitmentOp struct {
+	Key    []byte
+	Exists bool
+	Proof  *iavl.RangeProof
+}
+
+// NewCommitmentOp wraps an IAVL range proof for key into a composable Op.
+// exists records whether the store found key (Proof then attests to its
+// value) or not (Proof then attests to its absence).
+func NewCommitmentOp(key []byte, exists bool, rangeProof *iavl.RangeProof) CommitmentOp {
+	return CommitmentOp{Key: key, Exists: exists, Proof: rangeProof}
+}
+
+func (op CommitmentOp) Run(args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("commitment op expects exactly one argument, got %d", len(args))
+	}
+
+	if err := op.Proof.Verify(op.Proof.RootHash); err != nil {
+		return nil, fmt.Errorf("invalid IAVL range proof: %v", err)
+	}
+
+	if op.Exists {
+		if err := op.Proof.VerifyItem(op.Key, args[0]); err != nil {
+			return nil, fmt.Errorf("IAVL range proof does not cover key/value: %v", err)
+		}
+	} else {
+		if args[0] != nil {
+			return nil, fmt.Errorf("IAVL absence proof cannot verify a value for the key")
+		}
+		if err := op.Proof.VerifyAbsence(op.Key); err != nil {
+			return nil, fmt.Errorf("IAVL range proof does not prove key's absence: %v", err)
+		}
+	}
+
+	return [][]byte{op.Proof.RootHash}, nil
+}
+
+// commitmentOpWire is the JSON form of a CommitmentOp's Data: the
+// Exists/Proof pair ProofOp() writes and DecodeOps reads back.
+type commitmentOpWire struct {
+	Exists bool
+	Proof  *iavl.RangeProof
+}
+
+func (op CommitmentOp) ProofOp() ProofOp {
+	bz, err := json.Marshal(commitmentOpWire{Exists: op.Exists, Proof: op.Proof})
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal IAVL range proof: %v", err))
+	}
+
+	return ProofOp{Type: OpTypeIAVL, Key: op.Key, Data: bz}
+}
+
+// SimpleStoreOp proves that a substore's CommitID (the version and root hash
+// produced by the CommitmentOp below it) is included in the multistore root
+// computed by rootmulti's commitInfo.Hash/merkle.SimpleHashFromMap. Version
+// is carried alongside the proof because the multistore root is built over
+// leaves of {name, commitID}, not over bare root hashes.
+type SimpleStoreOp struct {
+	StoreName string
+	Version   int64
+	Proof     *merkle.SimpleProof
+}
+
+// NewSimpleStoreOp wraps the multistore-level Merkle proof for storeName,
+// committed at version, into a composable Op.
+func NewSimpleStoreOp(storeName string, version int64, simpleProof *merkle.SimpleProof) SimpleStoreOp {
+	return SimpleStoreOp{StoreName: storeName, Version: version, Proof: simpleProof}
+}
+
+func (op SimpleStoreOp) Run(args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("simple store op expects exactly one argument, got %d", len(args))
+	}
+
+	leaf := types.HashStoreInfo(op.StoreName, types.CommitID{Version: op.Version, Hash: args[0]})
+	return [][]byte{op.Proof.ComputeRootHash(leaf)}, nil
+}
+
+func (op SimpleStoreOp) ProofOp() ProofOp {
+	bz, err := json.Marshal(op)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal simple store proof: %v", err))
+	}
+
+	return ProofOp{Type: OpTypeSimpleStore, Key: []byte(op.StoreName), Data: bz}
+}
+
+// DecodeOps reverses ProofOp() for each op in pops, so a light client that
+// only has the wire-form ProofOps (e.g. from a query response) can rebuild
+// Ops to feed into a ProofRuntime.
+func DecodeOps(pops []ProofOp) ([]Op, error) {
+	ops := make([]Op, len(pops))
+
+	for i, pop := range pops {
+		switch pop.Type {
+		case OpTypeIAVL:
+			var wire commitmentOpWire
+			if err := json.Unmarshal(pop.Data, &wire); err != nil {
+				return nil, fmt.Errorf("decoding IAVL range proof: %v", err)
+			}
+			ops[i] = CommitmentOp{Key: pop.Key, Exists: wire.Exists, Proof: wire.Proof}
+
+		case OpTypeSimpleStore:
+			var sop SimpleStoreOp
+			if err := json.Unmarshal(pop.Data, &sop); err != nil {
+				return nil, fmt.Errorf("decoding simple store proof: %v", err)
+			}
+			ops[i] = sop
+
+		default:
+			return nil, fmt.Errorf("unrecognized proof op type %q", pop.Type)
+		}
+	}
+
+	return ops, nil
+}
+
+// ProofRuntime runs a proof-op chain against a value and checks it resolves
+// to the expected root.
+type ProofRuntime struct{}
+
+// NewProofRuntime returns a ProofRuntime ready to verify proof chains built
+// from this package's Ops.
+func NewProofRuntime() *ProofRuntime {
+	return &ProofRuntime{}
+}
+
+// VerifyValue runs value through each op in ops, in order (innermost/
+// substore proof first, outermost/multistore proof last), and checks the
+// final result equals root.
+func (prt *ProofRuntime) VerifyValue(ops []Op, root, value []byte) error {
+	args := [][]byte{value}
+
+	for _, op := range ops {
+		out, err := op.Run(args)
+		if err != nil {
+			return err
+		}
+		args = out
+	}
+
+	if len(args) != 1 || !bytes.Equal(args[0], root) {
+		return fmt.Errorf("proof chain did not resolve to the expected root")
+	}
+
+	return nil
+}
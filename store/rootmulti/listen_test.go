@@ -0,0 +1,45 @@
+package rootmulti
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/cosmos/cosmos-sdk/store/streaming"
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+func TestAddListenersEmitsWritesInOrder(t *testing.T) {
+	db := dbm.NewMemDB()
+	store := newMultiStoreWithMounts(db)
+	err := store.LoadLatestVersion()
+	require.Nil(t, err)
+
+	gss := streaming.NewGRPCStreamingService()
+	sub, unsubscribe := gss.Subscribe()
+	defer unsubscribe()
+
+	store.AddListeners(store.keysByName["store1"], []types.WriteListener{gss})
+	store.AddListeners(store.keysByName["store2"], []types.WriteListener{gss})
+
+	store1 := store.getStoreByName("store1").(types.KVStore)
+	store2 := store.getStoreByName("store2").(types.KVStore)
+
+	store1.Set([]byte("k1"), []byte("v1"))
+	store2.Set([]byte("k2"), []byte("v2"))
+	store1.Delete([]byte("k1"))
+
+	store.Commit()
+
+	want := []types.StoreKVPair{
+		{StoreKey: "store1", Key: []byte("k1"), Value: []byte("v1")},
+		{StoreKey: "store2", Key: []byte("k2"), Value: []byte("v2")},
+		{StoreKey: "store1", Key: []byte("k1"), Delete: true},
+	}
+
+	for _, w := range want {
+		got := <-sub
+		require.Equal(t, w, got)
+	}
+}
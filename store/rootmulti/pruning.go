@@ -0,0 +1,138 @@
+package rootmulti
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+)
+
+const pruneHeightsKey = "s/pruneheights"
+
+// PruningOptions controls how many historical IAVL versions a Store retains.
+// KeepRecent keeps the KeepRecent most recent versions around regardless of
+// anything else. KeepEvery, if non-zero, additionally keeps every version
+// that's a multiple of it, so operators can still query well-known
+// checkpoints (e.g. "every 100th block") even after recent history has
+// rolled off. Interval batches the actual deletions: eligible versions are
+// buffered and only deleted once height is itself a multiple of Interval.
+// Interval of 0 disables pruning entirely.
+type PruningOptions struct {
+	KeepRecent uint64
+	KeepEvery  uint64
+	Interval   uint64
+}
+
+var (
+	// PruneDefault keeps recent state and every 100th version for nodes that
+	// want to serve historical queries without keeping everything.
+	PruneDefault = PruningOptions{KeepRecent: 100, KeepEvery: 100, Interval: 10}
+
+	// PruneEverything keeps only the minimum needed for the chain to keep
+	// operating, pruning as aggressively as possible.
+	PruneEverything = PruningOptions{KeepRecent: 2, KeepEvery: 0, Interval: 10}
+
+	// PruneNothing disables pruning: every version is kept forever.
+	PruneNothing = PruningOptions{KeepRecent: 0, KeepEvery: 1, Interval: 0}
+)
+
+// prunableVersion reports the version (if any) that becomes eligible for
+// deletion now that height has been committed, or 0 if none does.
+func (opts PruningOptions) prunableVersion(height int64) int64 {
+	if opts.Interval == 0 {
+		return 0
+	}
+
+	candidate := height - int64(opts.KeepRecent)
+	if candidate <= 0 {
+		return 0
+	}
+
+	if opts.KeepEvery > 0 && candidate%int64(opts.KeepEvery) == 0 {
+		return 0
+	}
+
+	return candidate
+}
+
+// pruneSnapshot is called after every Commit. It buffers the version that
+// just became eligible for deletion and, once height lands on a pruning
+// Interval boundary, flushes the whole buffer by deleting those versions
+// from every mounted IAVL substore in a background goroutine. pruneMtx keeps
+// that goroutine from running concurrently with another prune pass, and
+// with Commit's own tree-commit and kvstores map mutation. getStoreByName
+// takes a read lock on it, so store lookups (and hence Query) wait while a
+// prune pass is running.
+//
+// The on-disk pending-heights buffer is only cleared once deleteVersions has
+// actually returned. Clearing it eagerly (before the deletion ran) would let
+// a crash between the two lose those heights for good: they'd be neither
+// deleted nor replayed by loadVersion's resume-on-load logic.
+func (rs *Store) pruneSnapshot(height int64) {
+	if rs.pruning.Interval == 0 {
+		return
+	}
+
+	if toPrune := rs.pruning.prunableVersion(height); toPrune > 0 {
+		rs.pruneHeights = append(rs.pruneHeights, toPrune)
+	}
+
+	if height%int64(rs.pruning.Interval) != 0 {
+		rs.persistPruneHeights()
+		return
+	}
+
+	heights := rs.pruneHeights
+	rs.pruneHeights = nil
+
+	if len(heights) == 0 {
+		rs.persistPruneHeights()
+		return
+	}
+
+	rs.pruneMtx.Lock()
+	go func() {
+		defer rs.pruneMtx.Unlock()
+		rs.deleteVersions(heights)
+		rs.persistPruneHeights()
+	}()
+}
+
+func (rs *Store) deleteVersions(heights []int64) {
+	for _, height := range heights {
+		for key, store := range rs.kvstores {
+			iavlStore, ok := store.(*iavl.Store)
+			if !ok {
+				continue
+			}
+			if err := iavlStore.DeleteVersion(height); err != nil {
+				panic(fmt.Sprintf("rootmulti: failed to prune version %d of store %q: %v", height, key.Name(), err))
+			}
+		}
+	}
+}
+
+func (rs *Store) persistPruneHeights() {
+	bz, err := json.Marshal(rs.pruneHeights)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal pending prune heights: %v", err))
+	}
+	rs.db.SetSync([]byte(pruneHeightsKey), bz)
+}
+
+// loadPruneHeights recovers the pending-prune buffer persisted by a previous
+// session, so a crash between buffering a height and flushing it at the
+// next Interval boundary doesn't lose track of that height.
+func (rs *Store) loadPruneHeights() []int64 {
+	bz := rs.db.Get([]byte(pruneHeightsKey))
+	if bz == nil {
+		return nil
+	}
+
+	var heights []int64
+	if err := json.Unmarshal(bz, &heights); err != nil {
+		panic(fmt.Sprintf("failed to unmarshal pending prune heights: %v", err))
+	}
+
+	return heights
+}
@@ -0,0 +1,86 @@
+package rootmulti
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+)
+
+func TestPrunableVersion(t *testing.T) {
+	cases := []struct {
+		name   string
+		opts   PruningOptions
+		height int64
+		want   int64
+	}{
+		{"nothing never prunes", PruneNothing, 1000, 0},
+		{"no interval configured never prunes", PruningOptions{KeepRecent: 10}, 15, 0},
+		{"not enough history yet", PruningOptions{KeepRecent: 10, Interval: 1}, 5, 0},
+		{"past KeepRecent", PruningOptions{KeepRecent: 10, Interval: 1}, 15, 5},
+		{"kept by KeepEvery", PruningOptions{KeepRecent: 10, KeepEvery: 5, Interval: 1}, 15, 0},
+		{"not kept by KeepEvery", PruningOptions{KeepRecent: 10, KeepEvery: 5, Interval: 1}, 16, 6},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.opts.prunableVersion(tc.height))
+		})
+	}
+}
+
+func TestPruningFlushesAtInterval(t *testing.T) {
+	presets := map[string]PruningOptions{
+		"default":    PruneDefault,
+		"everything": PruneEverything,
+		"nothing":    PruneNothing,
+	}
+
+	for name, opts := range presets {
+		t.Run(name, func(t *testing.T) {
+			db := dbm.NewMemDB()
+			store := NewStore(db, opts)
+			store.MountKVStoreWithDB(iavl.NewKey("store1"), nil)
+			require.Nil(t, store.LoadLatestVersion())
+
+			for i := 0; i < 30; i++ {
+				store.Commit()
+			}
+
+			if opts.Interval == 0 {
+				require.Nil(t, store.pruneHeights)
+				return
+			}
+
+			// Wait for the background goroutine the last interval boundary
+			// kicked off: it holds pruneMtx for its whole run (including
+			// the persist that clears the on-disk buffer), so acquiring and
+			// releasing it here blocks until the deletions have actually
+			// landed.
+			store.pruneMtx.Lock()
+			store.pruneMtx.Unlock()
+
+			// 30 is a multiple of every preset's Interval above, so the
+			// pending buffer must have been flushed by now.
+			require.Empty(t, store.pruneHeights)
+			require.Empty(t, store.loadPruneHeights())
+
+			if name != "everything" {
+				return
+			}
+
+			// PruneEverything (KeepRecent: 2) must have actually deleted the
+			// older versions' tree nodes, not just forgotten about them:
+			// loading any of them now fails...
+			for height := int64(1); height <= 28; height++ {
+				require.Error(t, store.LoadMultiStoreVersion(height), "version %d should have been pruned", height)
+			}
+			// ...while the KeepRecent window is still queryable.
+			for height := int64(29); height <= 30; height++ {
+				require.NoError(t, store.LoadMultiStoreVersion(height), "version %d should still be queryable", height)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package rootmulti
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+	"github.com/cosmos/cosmos-sdk/store/snapshot"
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	db := dbm.NewMemDB()
+	multi := newMultiStoreWithMounts(db)
+	err := multi.LoadLatestVersion()
+	require.Nil(t, err)
+
+	k, v := []byte("wind"), []byte("blows")
+	k2, v2 := []byte("water"), []byte("flows")
+
+	multi.getStoreByName("store1").(types.KVStore).Set(k, v)
+	multi.getStoreByName("store2").(types.KVStore).Set(k2, v2)
+
+	commitID := multi.Commit()
+
+	var buf bytes.Buffer
+	err = multi.Snapshot(uint64(commitID.Version), snapshot.NewWriter(&buf))
+	require.Nil(t, err)
+
+	restored := NewStore(dbm.NewMemDB())
+	restored.MountKVStoreWithDB(iavl.NewKey("store1"), nil)
+	restored.MountKVStoreWithDB(iavl.NewKey("store2"), nil)
+	restored.MountKVStoreWithDB(iavl.NewKey("store3"), nil)
+	err = restored.LoadLatestVersion()
+	require.Nil(t, err)
+
+	err = restored.Restore(uint64(commitID.Version), snapshot.Format, snapshot.NewReader(&buf))
+	require.Nil(t, err)
+
+	require.Equal(t, commitID, restored.LastCommitID())
+	require.Equal(t, v, restored.getStoreByName("store1").(types.KVStore).Get(k))
+	require.Equal(t, v2, restored.getStoreByName("store2").(types.KVStore).Get(k2))
+}
+
+// TestSnapshotRestoreAtHeight exercises restoring a snapshot taken above
+// version 1, which Restore must land on in a single commit rather than by
+// replaying every version up to it.
+func TestSnapshotRestoreAtHeight(t *testing.T) {
+	db := dbm.NewMemDB()
+	multi := newMultiStoreWithMounts(db)
+	err := multi.LoadLatestVersion()
+	require.Nil(t, err)
+
+	// An empty first commit, as a chain's first (empty) block would produce...
+	multi.Commit()
+
+	// ...then data lands in the second.
+	k, v := []byte("wind"), []byte("blows")
+	multi.getStoreByName("store1").(types.KVStore).Set(k, v)
+	commitID := multi.Commit()
+	require.Equal(t, int64(2), commitID.Version)
+
+	var buf bytes.Buffer
+	err = multi.Snapshot(uint64(commitID.Version), snapshot.NewWriter(&buf))
+	require.Nil(t, err)
+
+	restored := NewStore(dbm.NewMemDB())
+	restored.MountKVStoreWithDB(iavl.NewKey("store1"), nil)
+	restored.MountKVStoreWithDB(iavl.NewKey("store2"), nil)
+	restored.MountKVStoreWithDB(iavl.NewKey("store3"), nil)
+	err = restored.LoadLatestVersion()
+	require.Nil(t, err)
+
+	err = restored.Restore(uint64(commitID.Version), snapshot.Format, snapshot.NewReader(&buf))
+	require.Nil(t, err)
+
+	// The restored store reached version 2 via a single commit instead of
+	// the original's two real ones, but the commit hash still matches:
+	// SetInitialVersion makes that one commit land on version 2 directly,
+	// the same version (and hence hash) the original store ended up with.
+	require.Equal(t, commitID, restored.LastCommitID())
+	require.Equal(t, v, restored.getStoreByName("store1").(types.KVStore).Get(k))
+}
+
+// closableBuffer adapts a bytes.Buffer to io.WriteCloser so it can stand in
+// for a chunk file or network destination in tests.
+type closableBuffer struct {
+	bytes.Buffer
+}
+
+func (c *closableBuffer) Close() error { return nil }
+
+// TestSnapshotRestoreChunked exercises ChunkWriter end to end: a snapshot
+// rolled over across several small chunks must still concatenate back into
+// a stream Restore can resume from via io.MultiReader.
+func TestSnapshotRestoreChunked(t *testing.T) {
+	db := dbm.NewMemDB()
+	multi := newMultiStoreWithMounts(db)
+	err := multi.LoadLatestVersion()
+	require.Nil(t, err)
+
+	k, v := []byte("wind"), []byte("blows")
+	k2, v2 := []byte("water"), []byte("flows")
+	multi.getStoreByName("store1").(types.KVStore).Set(k, v)
+	multi.getStoreByName("store2").(types.KVStore).Set(k2, v2)
+	commitID := multi.Commit()
+
+	var chunks []*closableBuffer
+	cw := snapshot.NewChunkWriter(16, func() (io.WriteCloser, error) {
+		buf := &closableBuffer{}
+		chunks = append(chunks, buf)
+		return buf, nil
+	})
+
+	err = multi.Snapshot(uint64(commitID.Version), cw)
+	require.Nil(t, err)
+	require.Nil(t, cw.Close())
+	require.True(t, len(chunks) > 1, "expected the snapshot to roll over into multiple chunks")
+
+	readers := make([]io.Reader, len(chunks))
+	for i, c := range chunks {
+		readers[i] = bytes.NewReader(c.Bytes())
+	}
+
+	restored := NewStore(dbm.NewMemDB())
+	restored.MountKVStoreWithDB(iavl.NewKey("store1"), nil)
+	restored.MountKVStoreWithDB(iavl.NewKey("store2"), nil)
+	restored.MountKVStoreWithDB(iavl.NewKey("store3"), nil)
+	err = restored.LoadLatestVersion()
+	require.Nil(t, err)
+
+	err = restored.Restore(uint64(commitID.Version), snapshot.Format, snapshot.NewReader(io.MultiReader(readers...)))
+	require.Nil(t, err)
+
+	require.Equal(t, commitID, restored.LastCommitID())
+	require.Equal(t, v, restored.getStoreByName("store1").(types.KVStore).Get(k))
+	require.Equal(t, v2, restored.getStoreByName("store2").(types.KVStore).Get(k2))
+}
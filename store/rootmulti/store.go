@@ -0,0 +1,515 @@
+package rootmulti
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	dbm "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+	"github.com/cosmos/cosmos-sdk/store/listenkv"
+	"github.com/cosmos/cosmos-sdk/store/proof"
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+const latestVersionKey = "s/latest"
+
+var (
+	_ types.CommitMultiStore = (*Store)(nil)
+	_ types.Queryable        = (*Store)(nil)
+)
+
+// Store is a CommitMultiStore that mounts any number of named CommitKVStores
+// and persists their state together under a single, block-height-indexed
+// commit.
+type Store struct {
+	db             dbm.DB
+	lastCommitInfo commitInfo
+
+	storesParams map[types.KVStoreKey]storeParams
+	kvstores     map[types.KVStoreKey]types.CommitKVStore
+	keysByName   map[string]types.KVStoreKey
+
+	// removalMap holds keys whose substores were requested for deletion via
+	// StoreUpgrades. They stay mounted and queryable for the remainder of the
+	// block they were unmounted in, and are only dropped from storesParams,
+	// keysByName, and kvstores on the next Commit.
+	removalMap map[types.KVStoreKey]bool
+
+	// listeners holds the WriteListeners registered against each mounted
+	// store, consulted by getStoreByName to decide whether a substore needs
+	// wrapping in a listenkv.Store.
+	listeners map[types.KVStoreKey][]types.WriteListener
+
+	pruning      PruningOptions
+	pruneHeights []int64
+	pruneMtx     sync.RWMutex
+}
+
+// storeParams records how to (re)build a substore: its key, its kind, and
+// the db it should be loaded from (nil meaning "a prefix of the multistore's
+// own db").
+type storeParams struct {
+	key types.KVStoreKey
+	typ types.StoreType
+	db  dbm.DB
+}
+
+// NewStore returns a reference to a new Store, ready to have stores mounted
+// on it and loaded. By default it prunes nothing; pass a PruningOptions to
+// opt into a pruning strategy (see PruneDefault, PruneEverything).
+func NewStore(db dbm.DB, opts ...PruningOptions) *Store {
+	pruning := PruneNothing
+	if len(opts) > 0 {
+		pruning = opts[0]
+	}
+
+	return &Store{
+		db:           db,
+		storesParams: make(map[types.KVStoreKey]storeParams),
+		kvstores:     make(map[types.KVStoreKey]types.CommitKVStore),
+		keysByName:   make(map[string]types.KVStoreKey),
+		removalMap:   make(map[types.KVStoreKey]bool),
+		listeners:    make(map[types.KVStoreKey][]types.WriteListener),
+		pruning:      pruning,
+	}
+}
+
+// AddListeners registers ls against key, so every Set/Delete applied to that
+// substore (via the handle returned by getStoreByName) is reported to them.
+func (rs *Store) AddListeners(key types.KVStoreKey, ls []types.WriteListener) {
+	rs.listeners[key] = append(rs.listeners[key], ls...)
+}
+
+func (rs *Store) GetStoreType() types.StoreType {
+	return types.StoreTypeMulti
+}
+
+// MountKVStoreWithDB mounts a store of type IAVL under key. If db is nil, the
+// store's data lives in a prefix of the multistore's own db; otherwise db is
+// used directly, which is handy for tests and for stores that want their own
+// physical database file.
+func (rs *Store) MountKVStoreWithDB(key types.KVStoreKey, db dbm.DB) {
+	if key.Name() == "" {
+		panic("MountKVStoreWithDB() key name cannot be empty")
+	}
+	if _, ok := rs.keysByName[key.Name()]; ok {
+		panic(fmt.Sprintf("store duplicate store key name %v", key))
+	}
+
+	rs.storesParams[key] = storeParams{key: key, typ: types.StoreTypeIAVL, db: db}
+	rs.keysByName[key.Name()] = key
+}
+
+// LoadLatestVersion loads the most recently committed version of every
+// mounted store.
+func (rs *Store) LoadLatestVersion() error {
+	return rs.loadVersion(rs.getLatestVersion(), nil)
+}
+
+// LoadMultiStoreVersion loads a specific, previously committed version.
+func (rs *Store) LoadMultiStoreVersion(ver int64) error {
+	return rs.loadVersion(ver, nil)
+}
+
+// LoadLatestVersionAndUpgrade behaves like LoadLatestVersion, but first
+// applies the given StoreUpgrades so stores can be added, deleted, or
+// renamed without a manual database migration.
+func (rs *Store) LoadLatestVersionAndUpgrade(upgrades *StoreUpgrades) error {
+	return rs.loadVersion(rs.getLatestVersion(), upgrades)
+}
+
+func (rs *Store) loadVersion(ver int64, upgrades *StoreUpgrades) error {
+	var cInfo commitInfo
+
+	if ver != 0 {
+		cInfoBz := rs.db.Get(commitInfoKey(ver))
+		if cInfoBz == nil {
+			return fmt.Errorf("failed to load rootmulti store: no commit info found for version %d", ver)
+		}
+		if err := json.Unmarshal(cInfoBz, &cInfo); err != nil {
+			return fmt.Errorf("failed to unmarshal commit info: %v", err)
+		}
+	}
+
+	infosByName := make(map[string]storeInfo, len(cInfo.StoreInfos))
+	for _, si := range cInfo.StoreInfos {
+		infosByName[si.Name] = si
+	}
+
+	newStores := make(map[types.KVStoreKey]types.CommitKVStore, len(rs.storesParams))
+
+	for key, params := range rs.storesParams {
+		lookupName := key.Name()
+		if oldName := upgrades.RenamedFrom(key.Name()); oldName != "" {
+			lookupName = oldName
+
+			// The renamed store's data physically lives under the old
+			// name's prefix; loadCommitStoreFromParams below always loads
+			// from the new one, so without this it would open an empty
+			// tree instead of carrying the old store's history over.
+			if params.db == nil {
+				rs.migrateStorePrefix(oldName, key.Name())
+			}
+		}
+
+		if upgrades.IsAdded(key.Name()) {
+			if _, ok := infosByName[lookupName]; ok {
+				return fmt.Errorf("store %q is marked as added but already has persisted data", key.Name())
+			}
+		}
+
+		commitID := types.CommitID{}
+		if si, ok := infosByName[lookupName]; ok {
+			commitID = si.Core.CommitID
+		}
+
+		if upgrades.IsDeleted(key.Name()) {
+			rs.removalMap[key] = true
+		}
+
+		store, err := rs.loadCommitStoreFromParams(key, commitID, params)
+		if err != nil {
+			return fmt.Errorf("failed to load store %q: %v", key.Name(), err)
+		}
+
+		newStores[key] = store
+	}
+
+	rs.lastCommitInfo = cInfo
+	rs.kvstores = newStores
+
+	// Resume any prune that was buffered but never flushed before a crash or
+	// restart, rather than waiting for the next Interval boundary.
+	if pending := rs.loadPruneHeights(); len(pending) > 0 {
+		rs.deleteVersions(pending)
+		rs.pruneHeights = nil
+		rs.persistPruneHeights()
+	}
+
+	return nil
+}
+
+// migrateStorePrefix physically moves every entry stored under a renamed
+// substore's old key prefix to its new one, then deletes the old prefix so
+// the rename doesn't leave the old data behind under a name nothing loads
+// from again. It's idempotent: re-running it (e.g. because
+// LoadLatestVersionAndUpgrade is applied again before the rename is dropped
+// from StoreUpgrades) is a no-op the second time, since the old prefix is
+// already gone.
+func (rs *Store) migrateStorePrefix(oldName, newName string) {
+	oldPrefix := []byte("s/k:" + oldName + "/")
+	newPrefix := []byte("s/k:" + newName + "/")
+
+	iter := dbm.IteratePrefix(rs.db, oldPrefix)
+	defer iter.Close()
+
+	batch := rs.db.NewBatch()
+	defer batch.Close()
+
+	var oldKeys [][]byte
+	for ; iter.Valid(); iter.Next() {
+		suffix := iter.Key()[len(oldPrefix):]
+		newKey := make([]byte, 0, len(newPrefix)+len(suffix))
+		newKey = append(newKey, newPrefix...)
+		newKey = append(newKey, suffix...)
+		batch.Set(newKey, iter.Value())
+
+		oldKeys = append(oldKeys, append([]byte{}, iter.Key()...))
+	}
+
+	// Only delete the old entries once their copies are all queued in the
+	// same batch, so a crash partway through never loses data: either the
+	// whole move lands atomically, or the old prefix is untouched and the
+	// next load retries the copy from scratch.
+	for _, key := range oldKeys {
+		batch.Delete(key)
+	}
+
+	batch.WriteSync()
+}
+
+func (rs *Store) loadCommitStoreFromParams(key types.KVStoreKey, id types.CommitID, params storeParams) (types.CommitKVStore, error) {
+	db := params.db
+	if db == nil {
+		db = dbm.NewPrefixDB(rs.db, []byte("s/k:"+key.Name()+"/"))
+	}
+
+	switch params.typ {
+	case types.StoreTypeIAVL:
+		return iavl.LoadStore(db, id)
+	default:
+		return nil, fmt.Errorf("unrecognized store type %v", params.typ)
+	}
+}
+
+// Commit commits every mounted substore, persists the resulting multistore
+// commit info, and applies any pending StoreUpgrades deletions.
+func (rs *Store) Commit() types.CommitID {
+	version := rs.lastCommitInfo.Version + 1
+
+	// pruneMtx also guards the background prune goroutine spawned by
+	// pruneSnapshot below, which ranges over rs.kvstores and calls
+	// DeleteVersion on the same IAVL trees this commits/SaveVersions. Holding
+	// it here keeps a commit from ever running concurrently with a prune
+	// pass; it must be released before calling pruneSnapshot, since
+	// pruneSnapshot acquires it before spawning that goroutine.
+	rs.pruneMtx.Lock()
+
+	// Stores pending removal are committed one last time below (so their
+	// data is flushed), but must not show up in this version's commitInfo:
+	// the commit that drops them should also stop folding them into the
+	// multistore hash.
+	toCommit := make(map[types.KVStoreKey]types.CommitKVStore, len(rs.kvstores))
+	for key, store := range rs.kvstores {
+		if rs.removalMap[key] {
+			store.Commit()
+			continue
+		}
+		toCommit[key] = store
+	}
+
+	rs.lastCommitInfo = commitStores(version, toCommit)
+	rs.flushMetadata(version, rs.lastCommitInfo)
+
+	for key := range rs.removalMap {
+		delete(rs.kvstores, key)
+		delete(rs.storesParams, key)
+		delete(rs.keysByName, key.Name())
+	}
+	rs.removalMap = make(map[types.KVStoreKey]bool)
+
+	rs.pruneMtx.Unlock()
+
+	rs.pruneSnapshot(version)
+
+	return rs.lastCommitInfo.CommitID()
+}
+
+func (rs *Store) LastCommitID() types.CommitID {
+	if rs.lastCommitInfo.Version == 0 {
+		return types.CommitID{}
+	}
+	return rs.lastCommitInfo.CommitID()
+}
+
+func (rs *Store) getStoreByName(name string) types.CommitKVStore {
+	rs.pruneMtx.RLock()
+	defer rs.pruneMtx.RUnlock()
+
+	key, ok := rs.keysByName[name]
+	if !ok {
+		return nil
+	}
+
+	store := rs.kvstores[key]
+	if store == nil {
+		return nil
+	}
+
+	if ls := rs.listeners[key]; len(ls) > 0 {
+		return listenkv.NewStore(store, key, ls)
+	}
+
+	return store
+}
+
+// Query implements the ABCI query interface, routing a request of the form
+// "/<storeName>/<subpath>" to the named substore.
+func (rs *Store) Query(req abci.RequestQuery) (res abci.ResponseQuery) {
+	storeName, subpath, err := parsePath(req.Path)
+	if err != nil {
+		res.Code = uint32(types.ToABCICode(types.CodeUnknownRequest))
+		res.Log = err.Error()
+		return
+	}
+
+	store := rs.getStoreByName(storeName)
+	if store == nil {
+		res.Code = uint32(types.ToABCICode(types.CodeUnknownRequest))
+		res.Log = fmt.Sprintf("no such store: %s", storeName)
+		return
+	}
+
+	queryable, ok := store.(types.Queryable)
+	if !ok {
+		res.Code = uint32(types.ToABCICode(types.CodeUnknownRequest))
+		res.Log = fmt.Sprintf("store %s doesn't support queries", storeName)
+		return
+	}
+
+	req.Path = subpath
+	res = queryable.Query(req)
+
+	wantCode := uint32(types.ToABCICode(types.CodeOK))
+	if !req.Prove || res.Code != wantCode || len(res.Proof) == 0 {
+		return res
+	}
+
+	if err := rs.attachMultiStoreProof(storeName, &res); err != nil {
+		res.Code = uint32(types.ToABCICode(types.CodeUnknownRequest))
+		res.Log = err.Error()
+	}
+
+	return res
+}
+
+// attachMultiStoreProof extends res.Proof (so far just the substore's own
+// IAVL commitment proof op) with a second op proving that substore's
+// CommitID.Hash is included in the multistore root, so a light client can
+// verify the whole chain against just the block header's app hash.
+func (rs *Store) attachMultiStoreProof(storeName string, res *abci.ResponseQuery) error {
+	var ops []proof.ProofOp
+	if err := json.Unmarshal(res.Proof, &ops); err != nil {
+		return fmt.Errorf("failed to decode substore proof: %v", err)
+	}
+
+	storeCommitID := types.CommitID{}
+	found := false
+	for _, si := range rs.lastCommitInfo.StoreInfos {
+		if si.Name == storeName {
+			storeCommitID = si.Core.CommitID
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("no multistore proof available for store %s", storeName)
+	}
+
+	_, storeProofs := merkle.SimpleProofsFromMap(rs.lastCommitInfo.storeMap())
+
+	storeProof, ok := storeProofs[storeName]
+	if !ok {
+		return fmt.Errorf("no multistore proof available for store %s", storeName)
+	}
+
+	ops = append(ops, proof.NewSimpleStoreOp(storeName, storeCommitID.Version, storeProof).ProofOp())
+
+	bz, err := json.Marshal(ops)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proof chain: %v", err)
+	}
+
+	res.Proof = bz
+	res.Height = rs.lastCommitInfo.Version
+	return nil
+}
+
+// parsePath expects a query path of the form "/<storeName>[/<subpath>]" and
+// splits it into the store name and the remaining subpath (still carrying
+// its leading slash, so it can be parsed again one level down).
+func parsePath(path string) (storeName, subpath string, err error) {
+	if !strings.HasPrefix(path, "/") {
+		return storeName, subpath, fmt.Errorf("invalid path: %q, must start with /", path)
+	}
+
+	paths := strings.SplitN(path[1:], "/", 2)
+	storeName = paths[0]
+
+	if len(paths) == 2 {
+		subpath = "/" + paths[1]
+	}
+
+	return storeName, subpath, nil
+}
+
+func (rs *Store) getLatestVersion() int64 {
+	bz := rs.db.Get([]byte(latestVersionKey))
+	if bz == nil {
+		return 0
+	}
+
+	var latest int64
+	if err := json.Unmarshal(bz, &latest); err != nil {
+		panic(fmt.Sprintf("failed to unmarshal latest version: %v", err))
+	}
+
+	return latest
+}
+
+func (rs *Store) flushMetadata(version int64, cInfo commitInfo) {
+	batch := rs.db.NewBatch()
+	defer batch.Close()
+
+	cInfoBz, err := json.Marshal(cInfo)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal commit info: %v", err))
+	}
+	batch.Set(commitInfoKey(version), cInfoBz)
+
+	latestBz, err := json.Marshal(version)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal latest version: %v", err))
+	}
+	batch.Set([]byte(latestVersionKey), latestBz)
+
+	batch.Write()
+}
+
+func commitInfoKey(ver int64) []byte {
+	return []byte(fmt.Sprintf("s/%d", ver))
+}
+
+//-----------------------------------------------------------------------
+// commitInfo and storeInfo
+
+// commitInfo defines the multistore commit at a given version: the version
+// itself plus the CommitID of every substore that made it up.
+type commitInfo struct {
+	Version    int64
+	StoreInfos []storeInfo
+}
+
+func (ci commitInfo) Hash() []byte {
+	return merkle.SimpleHashFromMap(ci.storeMap())
+}
+
+func (ci commitInfo) storeMap() map[string]merkle.Hasher {
+	m := make(map[string]merkle.Hasher, len(ci.StoreInfos))
+	for _, si := range ci.StoreInfos {
+		m[si.Name] = si
+	}
+	return m
+}
+
+func (ci commitInfo) CommitID() types.CommitID {
+	return types.CommitID{
+		Version: ci.Version,
+		Hash:    ci.Hash(),
+	}
+}
+
+// storeCore holds the part of a substore's identity that rolls up into the
+// multistore's merkle root.
+type storeCore struct {
+	CommitID types.CommitID
+}
+
+// storeInfo is a named storeCore; it implements merkle.Hasher so a set of
+// them can be folded into the multistore root via merkle.SimpleHashFromMap.
+type storeInfo struct {
+	Name string
+	Core storeCore
+}
+
+func (si storeInfo) Hash() []byte {
+	return types.HashStoreInfo(si.Name, si.Core.CommitID)
+}
+
+func commitStores(version int64, storeMap map[types.KVStoreKey]types.CommitKVStore) commitInfo {
+	storeInfos := make([]storeInfo, 0, len(storeMap))
+
+	for key, store := range storeMap {
+		storeInfos = append(storeInfos, storeInfo{
+			Name: key.Name(),
+			Core: storeCore{CommitID: store.Commit()},
+		})
+	}
+
+	return commitInfo{Version: version, StoreInfos: storeInfos}
+}
@@ -0,0 +1,62 @@
+package rootmulti
+
+// StoreUpgrades defines a series of transformations to apply to the stores
+// mounted on a Store, applied via LoadLatestVersionAndUpgrade. It lets an
+// upgrade handler add, delete, or rename mounted stores at a specific block
+// height without requiring a manual database migration.
+type StoreUpgrades struct {
+	Added   []string
+	Renamed []StoreRename
+	Deleted []string
+}
+
+// StoreRename pairs the old and new name of a store being renamed in place.
+// The substore's data (and history) is carried over under the new key; old
+// queries against From stop resolving once the rename takes effect.
+type StoreRename struct {
+	From string
+	To   string
+}
+
+// IsAdded returns true if the given key is listed as newly added. loadVersion
+// consults it to guard against an "added" store aliasing a name that already
+// has persisted commit info: added stores are expected to start empty, so
+// that would mean stale data is about to leak into what should be fresh.
+func (upgrades *StoreUpgrades) IsAdded(key string) bool {
+	if upgrades == nil {
+		return false
+	}
+	for _, name := range upgrades.Added {
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDeleted returns true if the given key is slated for removal.
+func (upgrades *StoreUpgrades) IsDeleted(key string) bool {
+	if upgrades == nil {
+		return false
+	}
+	for _, name := range upgrades.Deleted {
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// RenamedFrom returns the old name a store was renamed from, if key is the
+// destination of a rename, or "" otherwise.
+func (upgrades *StoreUpgrades) RenamedFrom(key string) string {
+	if upgrades == nil {
+		return ""
+	}
+	for _, rename := range upgrades.Renamed {
+		if rename.To == key {
+			return rename.From
+		}
+	}
+	return ""
+}
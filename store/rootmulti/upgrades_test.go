@@ -0,0 +1,103 @@
+package rootmulti
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+func TestStoreUpgrade(t *testing.T) {
+	db := dbm.NewMemDB()
+
+	// Load the multistore with a few stores and commit a few blocks.
+	store := newMultiStoreWithMounts(db)
+	err := store.LoadLatestVersion()
+	require.Nil(t, err)
+
+	store.getStoreByName("store1").(types.KVStore).Set([]byte("k1"), []byte("v1"))
+	store.getStoreByName("store2").(types.KVStore).Set([]byte("k2"), []byte("v2"))
+	store.getStoreByName("store3").(types.KVStore).Set([]byte("k3"), []byte("v3"))
+
+	for i := 0; i < 2; i++ {
+		store.Commit()
+	}
+	ver := store.LastCommitID().Version
+
+	// Reload, dropping store2, adding store4, and renaming store3 to
+	// store3renamed.
+	store = NewStore(db)
+	store.MountKVStoreWithDB(iavl.NewKey("store1"), nil)
+	store.MountKVStoreWithDB(iavl.NewKey("store2"), nil)
+	store.MountKVStoreWithDB(iavl.NewKey("store3renamed"), nil)
+	store.MountKVStoreWithDB(iavl.NewKey("store4"), nil)
+
+	upgrades := &StoreUpgrades{
+		Added:   []string{"store4"},
+		Deleted: []string{"store2"},
+		Renamed: []StoreRename{{From: "store3", To: "store3renamed"}},
+	}
+	err = store.LoadLatestVersionAndUpgrade(upgrades)
+	require.Nil(t, err)
+
+	// store2 is still reachable for the remainder of this block...
+	require.NotNil(t, store.getStoreByName("store2"))
+	// ... store3renamed carries over store3's history, data included...
+	s3renamed := store.getStoreByName("store3renamed")
+	require.NotNil(t, s3renamed)
+	require.Equal(t, []byte("v3"), s3renamed.(types.KVStore).Get([]byte("k3")))
+	// ... and the rename physically moved the data: nothing is left behind
+	// under store3's old prefix.
+	require.False(t, dbm.IteratePrefix(db, []byte("s/k:store3/")).Valid())
+	// ... and store4 exists as a brand new, empty store.
+	require.NotNil(t, store.getStoreByName("store4"))
+	require.Nil(t, store.getStoreByName("store4").(types.KVStore).Get([]byte("k1")))
+
+	commitID := store.Commit()
+
+	// After the commit, store2 is gone for good.
+	require.Nil(t, store.getStoreByName("store2"))
+
+	// The resulting hash only reflects the surviving stores.
+	expectedHash := hashStores(store.kvstores)
+	require.Equal(t, expectedHash, commitID.Hash)
+
+	// Reloading from scratch drops store2 from storesParams entirely; trying
+	// to mount and load it again starts a brand new (empty) store rather
+	// than resurrecting the old data.
+	fresh := NewStore(db)
+	fresh.MountKVStoreWithDB(iavl.NewKey("store1"), nil)
+	fresh.MountKVStoreWithDB(iavl.NewKey("store3renamed"), nil)
+	fresh.MountKVStoreWithDB(iavl.NewKey("store4"), nil)
+	err = fresh.LoadLatestVersion()
+	require.Nil(t, err)
+	require.Equal(t, commitID, fresh.LastCommitID())
+
+	// Querying the old store2 path returns not-found, even at the height
+	// before it was removed.
+	qres := fresh.Query(abci.RequestQuery{Path: "/store2/key", Data: []byte("k2"), Height: ver})
+	require.Equal(t, types.ToABCICode(types.CodeUnknownRequest), types.ABCICodeType(qres.Code))
+}
+
+// TestStoreUpgradeAddedAliasesExistingData checks that marking a key as
+// Added is rejected if that name already has persisted commit info: an
+// added store is supposed to start empty, so a name collision means it
+// would instead resurrect someone else's old data.
+func TestStoreUpgradeAddedAliasesExistingData(t *testing.T) {
+	db := dbm.NewMemDB()
+
+	store := newMultiStoreWithMounts(db)
+	err := store.LoadLatestVersion()
+	require.Nil(t, err)
+	store.getStoreByName("store1").(types.KVStore).Set([]byte("k1"), []byte("v1"))
+	store.Commit()
+
+	reloaded := NewStore(db)
+	reloaded.MountKVStoreWithDB(iavl.NewKey("store1"), nil)
+	err = reloaded.LoadLatestVersionAndUpgrade(&StoreUpgrades{Added: []string{"store1"}})
+	require.Error(t, err)
+}
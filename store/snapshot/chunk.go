@@ -0,0 +1,82 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+)
+
+// ChunkWriter is a Writer that splits its output across a sequence of
+// underlying io.WriteClosers, rolling over to the next one (obtained from
+// next) once at least chunkSize bytes have been written to the current one.
+// This lets a caller stream a snapshot into fixed-size files or network
+// chunks; a Restore call can resume reading by concatenating the chunks back
+// together, e.g. via io.MultiReader.
+type ChunkWriter struct {
+	next      func() (io.WriteCloser, error)
+	chunkSize int64
+
+	cur     io.WriteCloser
+	written int64
+}
+
+// NewChunkWriter returns a ChunkWriter that calls next to obtain a new
+// destination every time chunkSize bytes have been written to the current
+// one.
+func NewChunkWriter(chunkSize int64, next func() (io.WriteCloser, error)) *ChunkWriter {
+	return &ChunkWriter{chunkSize: chunkSize, next: next}
+}
+
+func (cw *ChunkWriter) WriteMsg(item Item) error {
+	if cw.cur == nil || cw.written >= cw.chunkSize {
+		if err := cw.rollOver(); err != nil {
+			return err
+		}
+	}
+
+	bz, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(bz)))
+
+	n1, err := cw.cur.Write(lenBuf[:])
+	if err != nil {
+		return err
+	}
+
+	n2, err := cw.cur.Write(bz)
+	if err != nil {
+		return err
+	}
+
+	cw.written += int64(n1 + n2)
+	return nil
+}
+
+func (cw *ChunkWriter) rollOver() error {
+	if cw.cur != nil {
+		if err := cw.cur.Close(); err != nil {
+			return err
+		}
+	}
+
+	w, err := cw.next()
+	if err != nil {
+		return err
+	}
+
+	cw.cur = w
+	cw.written = 0
+	return nil
+}
+
+// Close closes the currently open chunk, if any.
+func (cw *ChunkWriter) Close() error {
+	if cw.cur == nil {
+		return nil
+	}
+	return cw.cur.Close()
+}
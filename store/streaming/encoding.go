@@ -0,0 +1,109 @@
+package streaming
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+// writePair writes pair to w as a length-prefixed record: a 4-byte
+// big-endian length followed by the marshaled StoreKVPair. This stands in
+// for the length-prefixed protobuf framing the real ADR-038 streaming
+// services use, with the same read-back guarantees.
+func writePair(w io.Writer, pair types.StoreKVPair) error {
+	bz := marshalPair(pair)
+
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(bz)))
+
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(bz)
+	return err
+}
+
+// readPair reads back a single record written by writePair.
+func readPair(r io.Reader) (types.StoreKVPair, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return types.StoreKVPair{}, err
+	}
+
+	bz := make([]byte, binary.BigEndian.Uint32(lenBuf[:]))
+	if _, err := io.ReadFull(r, bz); err != nil {
+		return types.StoreKVPair{}, err
+	}
+
+	return unmarshalPair(bz)
+}
+
+func marshalPair(pair types.StoreKVPair) []byte {
+	bz := make([]byte, 0, len(pair.StoreKey)+len(pair.Key)+len(pair.Value)+13)
+	bz = appendBytes(bz, []byte(pair.StoreKey))
+	bz = appendBytes(bz, pair.Key)
+	bz = appendBytes(bz, pair.Value)
+
+	if pair.Delete {
+		bz = append(bz, 1)
+	} else {
+		bz = append(bz, 0)
+	}
+
+	return bz
+}
+
+func unmarshalPair(bz []byte) (types.StoreKVPair, error) {
+	storeKey, bz, err := readBytes(bz)
+	if err != nil {
+		return types.StoreKVPair{}, err
+	}
+
+	key, bz, err := readBytes(bz)
+	if err != nil {
+		return types.StoreKVPair{}, err
+	}
+
+	value, bz, err := readBytes(bz)
+	if err != nil {
+		return types.StoreKVPair{}, err
+	}
+
+	if len(bz) != 1 {
+		return types.StoreKVPair{}, io.ErrUnexpectedEOF
+	}
+
+	return types.StoreKVPair{
+		StoreKey: string(storeKey),
+		Key:      key,
+		Value:    value,
+		Delete:   bz[0] == 1,
+	}, nil
+}
+
+func appendBytes(dst, src []byte) []byte {
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(src)))
+	dst = append(dst, lenBuf[:]...)
+	return append(dst, src...)
+}
+
+func readBytes(bz []byte) (value, rest []byte, err error) {
+	if len(bz) < 4 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+
+	n := binary.BigEndian.Uint32(bz[:4])
+	bz = bz[4:]
+
+	if uint32(len(bz)) < n {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+
+	if n == 0 {
+		return nil, bz, nil
+	}
+
+	return bz[:n], bz[n:], nil
+}
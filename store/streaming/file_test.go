@@ -0,0 +1,58 @@
+package streaming
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+)
+
+func TestFileStreamingService(t *testing.T) {
+	dir, err := os.MkdirTemp("", "streaming-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fss := NewFileStreamingService(dir)
+	store1 := iavl.NewKey("store1")
+	store2 := iavl.NewKey("store2")
+
+	fss.BeginBlock(7)
+	fss.OnWrite(store1, []byte("k1"), []byte("v1"), false)
+	fss.OnWrite(store2, []byte("k2"), []byte("v2"), false)
+	fss.OnWrite(store1, []byte("k1"), nil, true)
+	fss.EndBlock(7)
+
+	got1 := readAll(t, filepath.Join(dir, fmt.Sprintf("block-7-%s.dat", store1.Name())))
+	require.Equal(t, []string{"k1=v1", "k1=<deleted>"}, got1)
+
+	got2 := readAll(t, filepath.Join(dir, fmt.Sprintf("block-7-%s.dat", store2.Name())))
+	require.Equal(t, []string{"k2=v2"}, got2)
+}
+
+func readAll(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var out []string
+	for {
+		pair, err := readPair(f)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		if pair.Delete {
+			out = append(out, fmt.Sprintf("%s=<deleted>", pair.Key))
+		} else {
+			out = append(out, fmt.Sprintf("%s=%s", pair.Key, pair.Value))
+		}
+	}
+
+	return out
+}
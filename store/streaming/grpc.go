@@ -0,0 +1,85 @@
+package streaming
+
+import (
+	"sync"
+
+	"github.com/cosmos/cosmos-sdk/store/types"
+)
+
+var _ StreamingService = (*GRPCStreamingService)(nil)
+
+// GRPCStreamingService is a StreamingService that publishes the same
+// StoreKVPair stream a FileStreamingService would write to disk out to any
+// number of live subscribers instead. Subscribers are modeled here as
+// buffered channels rather than wire connections; each receives the pairs in
+// write order. BeginBlock and EndBlock do nothing, so the channel carries no
+// block boundaries.
+type GRPCStreamingService struct {
+	mtx         sync.Mutex
+	subscribers map[chan types.StoreKVPair]struct{}
+}
+
+// NewGRPCStreamingService returns a GRPCStreamingService with no
+// subscribers.
+func NewGRPCStreamingService() *GRPCStreamingService {
+	return &GRPCStreamingService{subscribers: make(map[chan types.StoreKVPair]struct{})}
+}
+
+// Subscribe registers a new subscriber and returns its channel along with an
+// unsubscribe function the caller must invoke when it's done listening.
+func (gss *GRPCStreamingService) Subscribe() (<-chan types.StoreKVPair, func()) {
+	ch := make(chan types.StoreKVPair, 64)
+
+	gss.mtx.Lock()
+	gss.subscribers[ch] = struct{}{}
+	gss.mtx.Unlock()
+
+	unsubscribe := func() {
+		gss.mtx.Lock()
+		defer gss.mtx.Unlock()
+
+		if _, ok := gss.subscribers[ch]; ok {
+			delete(gss.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+func (gss *GRPCStreamingService) BeginBlock(height int64) {}
+
+func (gss *GRPCStreamingService) OnWrite(storeKey types.StoreKey, key, value []byte, isDelete bool) {
+	pair := types.StoreKVPair{StoreKey: storeKey.Name(), Key: key, Value: value, Delete: isDelete}
+
+	gss.mtx.Lock()
+	defer gss.mtx.Unlock()
+
+	for ch := range gss.subscribers {
+		select {
+		case ch <- pair:
+		default:
+			// A slow subscriber must not block block processing, but letting
+			// it silently miss a pair would break the guarantee that the
+			// emitted stream matches the writes in order. Disconnect it
+			// instead: close its channel so the reader observes the drop as
+			// a closed (not merely stalled) stream, and stop tracking it so
+			// later writes don't keep selecting against it.
+			delete(gss.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (gss *GRPCStreamingService) EndBlock(height int64) {}
+
+func (gss *GRPCStreamingService) Close() error {
+	gss.mtx.Lock()
+	defer gss.mtx.Unlock()
+
+	for ch := range gss.subscribers {
+		delete(gss.subscribers, ch)
+		close(ch)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package streaming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/store/iavl"
+)
+
+// TestGRPCStreamingServiceLaggingSubscriberDisconnected checks that a
+// subscriber whose channel is full gets disconnected rather than silently
+// missing writes: OnWrite must close its channel instead of dropping the
+// pair, so the reader can tell its stream broke instead of reading a gap.
+func TestGRPCStreamingServiceLaggingSubscriberDisconnected(t *testing.T) {
+	gss := NewGRPCStreamingService()
+	sub, unsubscribe := gss.Subscribe()
+	defer unsubscribe()
+
+	store1 := iavl.NewKey("store1")
+
+	for i := 0; i < 65; i++ {
+		gss.OnWrite(store1, []byte("k"), []byte("v"), false)
+	}
+
+	for i := 0; i < 64; i++ {
+		_, ok := <-sub
+		require.True(t, ok, "expected the buffered pairs written before overflow to still be readable")
+	}
+
+	_, ok := <-sub
+	require.False(t, ok, "expected the channel to be closed once a write overflowed its buffer")
+}
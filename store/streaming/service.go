@@ -0,0 +1,17 @@
+package streaming
+
+import "github.com/cosmos/cosmos-sdk/store/types"
+
+// StreamingService observes every KV store write made during a block and
+// publishes it somewhere external (a file, a gRPC stream, ...). Whatever
+// owns the root multistore registers one or more of these via
+// rootmulti.Store.AddListeners at construction time, then drives
+// BeginBlock/EndBlock alongside its own ABCI lifecycle so the service can
+// frame the writes it receives per block.
+type StreamingService interface {
+	types.WriteListener
+
+	BeginBlock(height int64)
+	EndBlock(height int64)
+	Close() error
+}
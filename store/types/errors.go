@@ -0,0 +1,32 @@
+package types
+
+// CodeType mirrors the small slice of root-level ABCI result codes that the
+// store layer needs to produce on its own (e.g. an unmounted store name).
+// Application-level codes live alongside sdk.Error and are out of scope here.
+type CodeType uint32
+
+const (
+	CodeOK             CodeType = 0
+	CodeUnknownRequest CodeType = 6
+)
+
+// CodespaceType namespaces a CodeType so codes minted by different modules
+// don't collide once folded into a single uint32.
+type CodespaceType uint8
+
+const CodespaceRoot CodespaceType = 1
+
+// ABCICodeType is the uint32 actually carried on abci.ResponseQuery/Check/
+// DeliverTx, packing the codespace into the upper bits and the code into the
+// lower bits.
+type ABCICodeType uint32
+
+// ToABCICode folds code, under CodespaceRoot, into the wire ABCICodeType. CodeOK is
+// never folded: ABCI clients check a response's Code against the bare
+// constant 0 to mean success, regardless of codespace.
+func ToABCICode(code CodeType) ABCICodeType {
+	if code == CodeOK {
+		return ABCICodeType(CodeOK)
+	}
+	return ABCICodeType(uint32(CodespaceRoot)<<16 | uint32(code))
+}
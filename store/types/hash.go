@@ -0,0 +1,30 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+)
+
+// HashStoreInfo returns the leaf hash used to fold a single substore's
+// commit into a multistore root: the hash of {name, commitID}, encoded the
+// same way on both sides of the multistore's own SimpleHashFromMap call and
+// a light client's cross-store proof verification, so the two always agree
+// on what a given substore's leaf looks like.
+func HashStoreInfo(name string, commitID CommitID) []byte {
+	bz, err := json.Marshal(struct {
+		Name string
+		Core struct {
+			CommitID CommitID
+		}
+	}{
+		Name: name,
+		Core: struct{ CommitID CommitID }{CommitID: commitID},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	hasher := sha256.New()
+	hasher.Write(bz)
+	return hasher.Sum(nil)
+}
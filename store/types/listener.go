@@ -0,0 +1,20 @@
+package types
+
+// WriteListener is notified of every Set/Delete applied to a KVStore it has
+// been registered against, so external subsystems (e.g. state streaming) can
+// observe writes without the store itself knowing anything about them.
+type WriteListener interface {
+	// OnWrite is called after the write it describes has already been
+	// applied to the underlying store. value is nil when delete is true.
+	OnWrite(storeKey StoreKey, key, value []byte, delete bool)
+}
+
+// StoreKVPair is a single observed KVStore write, tagged with the name of
+// the store it happened in. It is the unit streamed out by the
+// implementations in store/streaming.
+type StoreKVPair struct {
+	StoreKey string
+	Key      []byte
+	Value    []byte
+	Delete   bool
+}
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	dbm "github.com/tendermint/tendermint/libs/db"
+)
+
+// Iterator is an alias of the Tendermint DB iterator, re-exported so callers
+// only need to import store/types.
+type Iterator = dbm.Iterator
+
+// StoreType defines the type of KVStore concrete implementation.
+type StoreType int
+
+const (
+	StoreTypeMulti StoreType = iota
+	StoreTypeDB
+	StoreTypeIAVL
+	StoreTypeTransient
+)
+
+// CommitID contains the tree version number and its merkle root.
+type CommitID struct {
+	Version int64
+	Hash    []byte
+}
+
+func (cid CommitID) IsZero() bool {
+	return cid.Version == 0 && len(cid.Hash) == 0
+}
+
+func (cid CommitID) String() string {
+	return fmt.Sprintf("CommitID{%v:%X}", cid.Hash, cid.Version)
+}
+
+// StoreKey identifies a mounted store. Different store implementations
+// provide their own constructors (e.g. iavl.NewKey).
+type StoreKey interface {
+	Name() string
+	String() string
+}
+
+// KVStoreKey is a concrete, comparable StoreKey used to mount KVStores. It is
+// intentionally a plain value (not a pointer) so it can be used directly as a
+// map key.
+type KVStoreKey struct {
+	name string
+}
+
+// NewKVStoreKey constructs a new KVStoreKey. Store packages wrap it in their
+// own constructor (e.g. iavl.NewKey); the key records only the name, not the
+// store type.
+func NewKVStoreKey(name string) KVStoreKey {
+	return KVStoreKey{name: name}
+}
+
+func (key KVStoreKey) Name() string { return key.name }
+
+func (key KVStoreKey) String() string {
+	return fmt.Sprintf("KVStoreKey{%q}", key.name)
+}
+
+// Store is the base interface implemented by every store.
+type Store interface {
+	GetStoreType() StoreType
+}
+
+// Committer reflects commitment status of a store.
+type Committer interface {
+	Commit() CommitID
+	LastCommitID() CommitID
+}
+
+// CommitStore is a store that can commit to disk and be loaded at a
+// previously committed version.
+type CommitStore interface {
+	Store
+	Committer
+}
+
+// KVStore is a simple key-value store.
+type KVStore interface {
+	Store
+
+	Get(key []byte) []byte
+	Has(key []byte) bool
+	Set(key, value []byte)
+	Delete(key []byte)
+
+	Iterator(start, end []byte) Iterator
+	ReverseIterator(start, end []byte) Iterator
+}
+
+// CommitKVStore is a KVStore that can also commit and reload.
+type CommitKVStore interface {
+	KVStore
+	Committer
+}
+
+// Queryable is implemented by stores that can answer ABCI queries against
+// their own data.
+type Queryable interface {
+	Query(abci.RequestQuery) abci.ResponseQuery
+}
+
+// CommitMultiStore is the set of behaviors every multistore implementation
+// (currently just rootmulti.Store) exposes in common. Mounting and loading
+// are intentionally left off this interface since different multistore
+// backends are free to shape those differently.
+type CommitMultiStore interface {
+	Store
+	Committer
+	Queryable
+}